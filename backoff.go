@@ -0,0 +1,176 @@
+// Copyright 2012-2014 Oliver Eilhard. All rights reserved.
+// Use of this source code is governed by a MIT-license.
+// See http://olivere.mit-license.org/license.txt for details.
+
+package elastic
+
+import (
+	"bytes"
+	"fmt"
+	"io/ioutil"
+	"math/rand"
+	"net/http"
+	"sync"
+	"time"
+)
+
+// Backoff determines how long to wait before retrying a failed request,
+// or whether to give up entirely. Next is called with the number of the
+// retry (starting at 0) and returns the delay to wait and whether another
+// attempt should be made at all.
+type Backoff interface {
+	// Next returns the duration to wait before retrying the given attempt,
+	// and false if no more retries should be attempted.
+	Next(retry int) (time.Duration, bool)
+}
+
+// ConstantBackoff waits for a fixed amount of time between retries, up to
+// a maximum number of attempts.
+type ConstantBackoff struct {
+	interval   time.Duration
+	maxRetries int
+}
+
+// NewConstantBackoff creates a Backoff that always waits for the given
+// interval and gives up after maxRetries attempts.
+func NewConstantBackoff(interval time.Duration, maxRetries int) *ConstantBackoff {
+	return &ConstantBackoff{interval: interval, maxRetries: maxRetries}
+}
+
+// Next implements Backoff.
+func (b *ConstantBackoff) Next(retry int) (time.Duration, bool) {
+	if retry >= b.maxRetries {
+		return 0, false
+	}
+	return b.interval, true
+}
+
+// SimpleBackoff takes a list of fixed delays and returns them in order,
+// one per retry. Once the list is exhausted, it gives up.
+type SimpleBackoff struct {
+	mu     sync.Mutex
+	delays []time.Duration
+}
+
+// NewSimpleBackoff creates a Backoff from a fixed list of delays, specified
+// in milliseconds.
+func NewSimpleBackoff(delaysInMillis ...int) *SimpleBackoff {
+	delays := make([]time.Duration, len(delaysInMillis))
+	for i, d := range delaysInMillis {
+		delays[i] = time.Duration(d) * time.Millisecond
+	}
+	return &SimpleBackoff{delays: delays}
+}
+
+// Next implements Backoff.
+func (b *SimpleBackoff) Next(retry int) (time.Duration, bool) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	if retry >= len(b.delays) {
+		return 0, false
+	}
+	return b.delays[retry], true
+}
+
+// ExponentialBackoff waits exponentially longer between every retry,
+// doubling the delay on each attempt up to maxDelay, and applies full
+// jitter so that many clients retrying at once don't all wake up at the
+// same time (the "thundering herd" problem).
+type ExponentialBackoff struct {
+	initial    time.Duration
+	max        time.Duration
+	maxRetries int
+}
+
+// NewExponentialBackoff creates a Backoff that starts at initial, doubles
+// on every retry, never exceeds max, and gives up after maxRetries attempts.
+func NewExponentialBackoff(initial, max time.Duration, maxRetries int) *ExponentialBackoff {
+	return &ExponentialBackoff{initial: initial, max: max, maxRetries: maxRetries}
+}
+
+// Next implements Backoff.
+func (b *ExponentialBackoff) Next(retry int) (time.Duration, bool) {
+	if retry >= b.maxRetries {
+		return 0, false
+	}
+	delay := b.initial * time.Duration(1<<uint(retry))
+	if delay > b.max || delay <= 0 {
+		delay = b.max
+	}
+	// Full jitter: pick a random duration in [0, delay].
+	return time.Duration(rand.Int63n(int64(delay) + 1)), true
+}
+
+// Retrier decides, after a failed request, whether the Client should retry
+// it and how long to wait first. It is the interface implementations of
+// Backoff are adapted to before being plugged into a Client via SetRetrier.
+type Retrier interface {
+	// Next is called after a failed attempt (retry 0 being the first
+	// retry, not the original attempt) and returns the delay to wait
+	// before trying again, plus whether a retry should be attempted.
+	Next(retry int) (time.Duration, bool)
+}
+
+// noRetrier never retries. It is the default Retrier on a Client so that
+// existing callers keep the current, non-retrying semantics unless they
+// opt in via SetRetrier.
+type noRetrier struct{}
+
+// Next implements Retrier.
+func (noRetrier) Next(retry int) (time.Duration, bool) {
+	return 0, false
+}
+
+// BackoffRetrier adapts a Backoff to the Retrier interface, so any of the
+// Backoff implementations above can be passed to Client.SetRetrier.
+type BackoffRetrier struct {
+	backoff Backoff
+}
+
+// NewBackoffRetrier creates a Retrier that delegates to the given Backoff.
+func NewBackoffRetrier(backoff Backoff) *BackoffRetrier {
+	return &BackoffRetrier{backoff: backoff}
+}
+
+// Next implements Retrier.
+func (r *BackoffRetrier) Next(retry int) (time.Duration, bool) {
+	return r.backoff.Next(retry)
+}
+
+// retryOnError inspects the outcome of an HTTP round trip and returns a
+// non-nil error if the call is eligible for retry: connection errors,
+// 502/503/504 responses, or an ES-reported "no shard available" error.
+// A nil return means the caller should proceed with the response as-is.
+func retryOnError(err error, res *http.Response) error {
+	if err != nil {
+		return err
+	}
+	if res == nil {
+		return nil
+	}
+	switch res.StatusCode {
+	case http.StatusBadGateway, http.StatusServiceUnavailable, http.StatusGatewayTimeout:
+		return fmt.Errorf("elastic: server returned %d", res.StatusCode)
+	}
+	if res.StatusCode >= 400 && res.Body != nil {
+		body, rerr := ioutil.ReadAll(res.Body)
+		res.Body.Close()
+		res.Body = ioutil.NopCloser(bytes.NewReader(body))
+		if rerr == nil && (bytes.Contains(body, []byte("NoShardAvailableActionException")) ||
+			bytes.Contains(body, []byte("no_shard_available_action_exception"))) {
+			return fmt.Errorf("elastic: no shard available (server returned %d)", res.StatusCode)
+		}
+	}
+	return nil
+}
+
+// SetRetrier changes the Retrier used to decide whether and how long to
+// wait before retrying a failed request. The default is a no-op retrier
+// that preserves the existing behavior of failing on the first error.
+func (c *Client) SetRetrier(retrier Retrier) *Client {
+	if retrier == nil {
+		retrier = noRetrier{}
+	}
+	c.retrier = retrier
+	return c
+}
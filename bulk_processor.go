@@ -0,0 +1,441 @@
+// Copyright 2012-2014 Oliver Eilhard. All rights reserved.
+// Use of this source code is governed by a MIT-license.
+// See http://olivere.mit-license.org/license.txt for details.
+
+package elastic
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+// BulkBeforeFunc is called before a bulk request is committed to Elasticsearch.
+type BulkBeforeFunc func(executionId int64, requests []BulkableRequest)
+
+// BulkAfterFunc is called after a bulk request has been committed to
+// Elasticsearch, or failed to be committed entirely (in which case err
+// is set and response is nil).
+type BulkAfterFunc func(executionId int64, requests []BulkableRequest, response *bulkResponse, err error)
+
+// BulkProcessorService builds a BulkProcessor.
+type BulkProcessorService struct {
+	client        *Client
+	name          string
+	bulkActions   int
+	bulkSize      int
+	flushInterval time.Duration
+	workers       int
+	backoff       Backoff
+	before        BulkBeforeFunc
+	after         BulkAfterFunc
+}
+
+// NewBulkProcessorService creates a new BulkProcessorService that batches
+// index/update/delete requests in the background, using sensible defaults
+// for action count, byte size, and number of workers.
+func NewBulkProcessorService(client *Client) *BulkProcessorService {
+	return &BulkProcessorService{
+		client:      client,
+		bulkActions: 1000,
+		bulkSize:    5 << 20, // 5MB
+		workers:     1,
+		backoff:     NewExponentialBackoff(100*time.Millisecond, 10*time.Second, 5),
+	}
+}
+
+// Name sets a human-readable name for the processor, used in logging.
+func (s *BulkProcessorService) Name(name string) *BulkProcessorService {
+	s.name = name
+	return s
+}
+
+// Workers sets the number of concurrent workers flushing bulk requests.
+func (s *BulkProcessorService) Workers(num int) *BulkProcessorService {
+	s.workers = num
+	return s
+}
+
+// BulkActions sets the number of requests that triggers a flush.
+func (s *BulkProcessorService) BulkActions(bulkActions int) *BulkProcessorService {
+	s.bulkActions = bulkActions
+	return s
+}
+
+// BulkSize sets the estimated byte size that triggers a flush.
+func (s *BulkProcessorService) BulkSize(bulkSize int) *BulkProcessorService {
+	s.bulkSize = bulkSize
+	return s
+}
+
+// FlushInterval sets a fixed interval after which pending requests are
+// flushed regardless of BulkActions/BulkSize. A zero interval disables
+// the periodic flush.
+func (s *BulkProcessorService) FlushInterval(interval time.Duration) *BulkProcessorService {
+	s.flushInterval = interval
+	return s
+}
+
+// Backoff sets the Backoff used to retry items that failed within a bulk
+// response (e.g. because a shard was temporarily unavailable).
+func (s *BulkProcessorService) Backoff(backoff Backoff) *BulkProcessorService {
+	s.backoff = backoff
+	return s
+}
+
+// Before sets a callback invoked right before a bulk request is sent.
+func (s *BulkProcessorService) Before(fn BulkBeforeFunc) *BulkProcessorService {
+	s.before = fn
+	return s
+}
+
+// After sets a callback invoked right after a bulk request returns.
+func (s *BulkProcessorService) After(fn BulkAfterFunc) *BulkProcessorService {
+	s.after = fn
+	return s
+}
+
+// Do builds the BulkProcessor and starts its background workers.
+func (s *BulkProcessorService) Do() (*BulkProcessor, error) {
+	p := &BulkProcessor{
+		c:             s.client,
+		name:          s.name,
+		bulkActions:   s.bulkActions,
+		bulkSize:      s.bulkSize,
+		flushInterval: s.flushInterval,
+		workers:       s.workers,
+		backoff:       s.backoff,
+		before:        s.before,
+		after:         s.after,
+	}
+	if err := p.Start(); err != nil {
+		return nil, err
+	}
+	return p, nil
+}
+
+// BulkProcessor batches index/update/delete requests in the background and
+// flushes them to Elasticsearch once the configured number of actions,
+// byte size, or time interval is reached, whichever comes first. It sits
+// on top of the plain, single-document IndexService and is the tool of
+// choice for high-throughput ingestion pipelines.
+type BulkProcessor struct {
+	// executionId is incremented via sync/atomic and must stay first in
+	// the struct so it's 64-bit aligned on 32-bit platforms.
+	executionId int64
+
+	c             *Client
+	name          string
+	bulkActions   int
+	bulkSize      int
+	flushInterval time.Duration
+	workers       int
+	backoff       Backoff
+	before        BulkBeforeFunc
+	after         BulkAfterFunc
+
+	mu         sync.Mutex
+	started    bool
+	requestsCh chan BulkableRequest
+	flushCh    chan chan error
+	stopCh     chan struct{}
+	wg         sync.WaitGroup
+}
+
+// Start launches the worker goroutines. It is called automatically by
+// BulkProcessorService.Do and is idempotent.
+func (p *BulkProcessor) Start() error {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	if p.started {
+		return nil
+	}
+
+	p.requestsCh = make(chan BulkableRequest)
+	p.flushCh = make(chan chan error)
+	p.stopCh = make(chan struct{})
+
+	for i := 0; i < p.workers; i++ {
+		p.wg.Add(1)
+		w := newBulkWorker(p, i)
+		go w.work()
+	}
+
+	p.started = true
+	return nil
+}
+
+// Stop is an alias for Close.
+func (p *BulkProcessor) Stop() error {
+	return p.Close()
+}
+
+// Close stops the background workers, flushing any pending requests first.
+// requestsCh is never closed (only stopCh is), so a concurrent or
+// subsequent Add never panics with a send on a closed channel. p.mu is
+// released before waiting on the workers: a worker's stopCh case commits
+// its buffered requests, which calls nextExecutionId, so holding p.mu
+// across wg.Wait would deadlock against that call.
+func (p *BulkProcessor) Close() error {
+	p.mu.Lock()
+	if !p.started {
+		p.mu.Unlock()
+		return nil
+	}
+	close(p.stopCh)
+	p.started = false
+	p.mu.Unlock()
+
+	p.wg.Wait()
+	return nil
+}
+
+// Flush blocks until a single worker has committed the requests it has
+// buffered. With more than one worker, requests buffered by the other
+// workers are not committed by this call.
+func (p *BulkProcessor) Flush() error {
+	p.mu.Lock()
+	started := p.started
+	p.mu.Unlock()
+	if !started {
+		return nil
+	}
+	errCh := make(chan error, 1)
+	p.flushCh <- errCh
+	return <-errCh
+}
+
+// Add enqueues a single index/update/delete request to be flushed in the
+// background according to the processor's thresholds. It is a no-op once
+// the processor has been stopped, so callers racing a Close don't block
+// forever or panic sending on a closed channel.
+func (p *BulkProcessor) Add(request BulkableRequest) {
+	p.mu.Lock()
+	started := p.started
+	ch := p.requestsCh
+	stop := p.stopCh
+	p.mu.Unlock()
+	if !started {
+		return
+	}
+	select {
+	case ch <- request:
+	case <-stop:
+	}
+}
+
+// bulkWorker owns one goroutine that accumulates requests and commits them
+// once BulkActions, BulkSize, or FlushInterval is exceeded.
+type bulkWorker struct {
+	p         *BulkProcessor
+	id        int
+	requests  []BulkableRequest
+	sizeBytes int
+}
+
+func newBulkWorker(p *BulkProcessor, id int) *bulkWorker {
+	return &bulkWorker{p: p, id: id}
+}
+
+func (w *bulkWorker) work() {
+	defer w.p.wg.Done()
+
+	var ticker *time.Ticker
+	var tickerCh <-chan time.Time
+	if w.p.flushInterval > 0 {
+		ticker = time.NewTicker(w.p.flushInterval)
+		tickerCh = ticker.C
+		defer ticker.Stop()
+	}
+
+	for {
+		select {
+		case req := <-w.p.requestsCh:
+			w.add(req)
+
+		case errCh := <-w.p.flushCh:
+			errCh <- w.commit()
+
+		case <-tickerCh:
+			w.commit()
+
+		case <-w.p.stopCh:
+			w.commit()
+			return
+		}
+	}
+}
+
+func (w *bulkWorker) add(req BulkableRequest) {
+	w.requests = append(w.requests, req)
+	if lines, err := req.Source(); err == nil {
+		for _, line := range lines {
+			w.sizeBytes += len(line) + 1
+		}
+	}
+	if len(w.requests) >= w.p.bulkActions || (w.p.bulkSize > 0 && w.sizeBytes >= w.p.bulkSize) {
+		w.commit()
+	}
+}
+
+// nextExecutionId is called from bulkWorker.commit, including from the
+// stopCh case of work's select loop while Close is blocked in wg.Wait --
+// so it must not take p.mu, or Close and a draining worker would deadlock
+// against each other.
+func (w *bulkWorker) nextExecutionId() int64 {
+	return atomic.AddInt64(&w.p.executionId, 1)
+}
+
+// commit sends the currently buffered requests as a single _bulk call,
+// retrying failed items using the processor's Backoff.
+func (w *bulkWorker) commit() error {
+	if len(w.requests) == 0 {
+		return nil
+	}
+	requests := w.requests
+	w.requests = nil
+	w.sizeBytes = 0
+
+	executionId := w.nextExecutionId()
+	if w.p.before != nil {
+		w.p.before(executionId, requests)
+	}
+
+	res, err := w.send(requests)
+
+	if w.p.after != nil {
+		w.p.after(executionId, requests, res, err)
+	}
+	return err
+}
+
+// send performs a _bulk call for a batch, retrying transport-level
+// failures (connection errors, 502/503/504) via sendOnce, and then
+// re-queuing any individual items the cluster reported as failed (e.g.
+// a version conflict or a 429) until they succeed or the processor's
+// Backoff gives up on them.
+func (w *bulkWorker) send(requests []BulkableRequest) (*bulkResponse, error) {
+	result, err := w.sendOnce(requests)
+	if err != nil {
+		return nil, err
+	}
+	if w.p.backoff == nil {
+		return result, nil
+	}
+
+	for retry := 0; result.Errors; retry++ {
+		failedIdx, failedReqs := failedItems(requests, result)
+		if len(failedReqs) == 0 {
+			break
+		}
+		wait, ok := w.p.backoff.Next(retry)
+		if !ok {
+			break
+		}
+		time.Sleep(wait)
+
+		retryResult, err := w.sendOnce(failedReqs)
+		if err != nil {
+			break
+		}
+		mergeResults(result, failedIdx, retryResult)
+	}
+	return result, nil
+}
+
+// sendOnce performs the actual HTTP round trip for a batch, retrying on
+// transport-level failures (connection errors, 502/503/504) according to
+// the processor's Backoff.
+func (w *bulkWorker) sendOnce(requests []BulkableRequest) (*bulkResponse, error) {
+	var buf bytes.Buffer
+	for _, req := range requests {
+		lines, err := req.Source()
+		if err != nil {
+			return nil, err
+		}
+		for _, line := range lines {
+			buf.WriteString(line)
+			buf.WriteByte('\n')
+		}
+	}
+
+	var lastErr error
+	for retry := 0; ; retry++ {
+		httpReq, err := w.p.c.NewRequest("POST", "/_bulk")
+		if err != nil {
+			return nil, err
+		}
+		httpReq.SetBodyString(buf.String())
+
+		res, err := w.p.c.doWithPool((*http.Request)(httpReq))
+		lastErr = retryOnError(err, res)
+		if lastErr == nil {
+			defer res.Body.Close()
+			ret := new(bulkResponse)
+			if err := json.NewDecoder(res.Body).Decode(ret); err != nil {
+				return nil, err
+			}
+			return ret, nil
+		}
+		if res != nil {
+			res.Body.Close()
+		}
+		if w.p.backoff == nil {
+			return nil, lastErr
+		}
+		wait, ok := w.p.backoff.Next(retry)
+		if !ok {
+			return nil, fmt.Errorf("elastic: bulk commit failed after %d retries: %v", retry, lastErr)
+		}
+		time.Sleep(wait)
+	}
+}
+
+// itemFailed reports whether a single bulk response item failed, i.e. its
+// status is outside the 2xx range or it carries an error.
+func itemFailed(item map[string]*bulkResultItem) bool {
+	for _, r := range item {
+		if r.Error != "" || r.Status >= 300 {
+			return true
+		}
+	}
+	return false
+}
+
+// failedItems returns the subset of requests (and their indices into
+// result.Items) that failed, so they can be resent on their own.
+func failedItems(requests []BulkableRequest, result *bulkResponse) ([]int, []BulkableRequest) {
+	var idx []int
+	var reqs []BulkableRequest
+	for i, item := range result.Items {
+		if i >= len(requests) {
+			break
+		}
+		if itemFailed(item) {
+			idx = append(idx, i)
+			reqs = append(reqs, requests[i])
+		}
+	}
+	return idx, reqs
+}
+
+// mergeResults overwrites the items in result that were retried with the
+// outcome of retryResult, and recomputes result.Errors accordingly.
+func mergeResults(result *bulkResponse, idx []int, retryResult *bulkResponse) {
+	for i, pos := range idx {
+		if i < len(retryResult.Items) {
+			result.Items[pos] = retryResult.Items[i]
+		}
+	}
+	result.Errors = false
+	for _, item := range result.Items {
+		if itemFailed(item) {
+			result.Errors = true
+			break
+		}
+	}
+}
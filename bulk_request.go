@@ -0,0 +1,286 @@
+// Copyright 2012-2014 Oliver Eilhard. All rights reserved.
+// Use of this source code is governed by a MIT-license.
+// See http://olivere.mit-license.org/license.txt for details.
+
+package elastic
+
+import "encoding/json"
+
+// BulkableRequest is implemented by the concrete bulk request types
+// (BulkIndexRequest, BulkUpdateRequest, BulkDeleteRequest) and renders a
+// single bulk item into the lines the _bulk API expects: an action/meta
+// line, optionally followed by a source line.
+// See http://www.elasticsearch.org/guide/en/elasticsearch/reference/current/docs-bulk.html
+type BulkableRequest interface {
+	Source() ([]string, error)
+}
+
+// BulkIndexRequest is a request to add or replace a document in a bulk call.
+type BulkIndexRequest struct {
+	index, typ, id string
+	routing        string
+	parent         string
+	opType         string
+	doc            interface{}
+}
+
+// NewBulkIndexRequest creates a new BulkIndexRequest. OpType defaults to
+// "index"; use OpType("create") to fail if the document already exists.
+func NewBulkIndexRequest() *BulkIndexRequest {
+	return &BulkIndexRequest{opType: "index"}
+}
+
+func (r *BulkIndexRequest) Index(index string) *BulkIndexRequest {
+	r.index = index
+	return r
+}
+
+func (r *BulkIndexRequest) Type(typ string) *BulkIndexRequest {
+	r.typ = typ
+	return r
+}
+
+func (r *BulkIndexRequest) Id(id string) *BulkIndexRequest {
+	r.id = id
+	return r
+}
+
+func (r *BulkIndexRequest) Routing(routing string) *BulkIndexRequest {
+	r.routing = routing
+	return r
+}
+
+func (r *BulkIndexRequest) Parent(parent string) *BulkIndexRequest {
+	r.parent = parent
+	return r
+}
+
+func (r *BulkIndexRequest) OpType(opType string) *BulkIndexRequest {
+	r.opType = opType
+	return r
+}
+
+func (r *BulkIndexRequest) Doc(doc interface{}) *BulkIndexRequest {
+	r.doc = doc
+	return r
+}
+
+// Source implements BulkableRequest.
+func (r *BulkIndexRequest) Source() ([]string, error) {
+	meta := map[string]interface{}{}
+	if r.index != "" {
+		meta["_index"] = r.index
+	}
+	if r.typ != "" {
+		meta["_type"] = r.typ
+	}
+	if r.id != "" {
+		meta["_id"] = r.id
+	}
+	if r.routing != "" {
+		meta["_routing"] = r.routing
+	}
+	if r.parent != "" {
+		meta["_parent"] = r.parent
+	}
+	action := map[string]interface{}{r.opType: meta}
+
+	actionLine, err := json.Marshal(action)
+	if err != nil {
+		return nil, err
+	}
+	lines := []string{string(actionLine)}
+
+	if r.doc != nil {
+		sourceLine, err := json.Marshal(r.doc)
+		if err != nil {
+			return nil, err
+		}
+		lines = append(lines, string(sourceLine))
+	}
+	return lines, nil
+}
+
+// BulkUpdateRequest is a request to update a document in a bulk call.
+type BulkUpdateRequest struct {
+	index, typ, id string
+	routing        string
+	parent         string
+	doc            interface{}
+	docAsUpsert    bool
+	upsert         interface{}
+}
+
+// NewBulkUpdateRequest creates a new BulkUpdateRequest.
+func NewBulkUpdateRequest() *BulkUpdateRequest {
+	return &BulkUpdateRequest{}
+}
+
+func (r *BulkUpdateRequest) Index(index string) *BulkUpdateRequest {
+	r.index = index
+	return r
+}
+
+func (r *BulkUpdateRequest) Type(typ string) *BulkUpdateRequest {
+	r.typ = typ
+	return r
+}
+
+func (r *BulkUpdateRequest) Id(id string) *BulkUpdateRequest {
+	r.id = id
+	return r
+}
+
+func (r *BulkUpdateRequest) Routing(routing string) *BulkUpdateRequest {
+	r.routing = routing
+	return r
+}
+
+func (r *BulkUpdateRequest) Parent(parent string) *BulkUpdateRequest {
+	r.parent = parent
+	return r
+}
+
+func (r *BulkUpdateRequest) Doc(doc interface{}) *BulkUpdateRequest {
+	r.doc = doc
+	return r
+}
+
+// DocAsUpsert indicates whether Doc should be used as the upsert value
+// when the document does not yet exist.
+func (r *BulkUpdateRequest) DocAsUpsert(docAsUpsert bool) *BulkUpdateRequest {
+	r.docAsUpsert = docAsUpsert
+	return r
+}
+
+func (r *BulkUpdateRequest) Upsert(upsert interface{}) *BulkUpdateRequest {
+	r.upsert = upsert
+	return r
+}
+
+// Source implements BulkableRequest.
+func (r *BulkUpdateRequest) Source() ([]string, error) {
+	meta := map[string]interface{}{}
+	if r.index != "" {
+		meta["_index"] = r.index
+	}
+	if r.typ != "" {
+		meta["_type"] = r.typ
+	}
+	if r.id != "" {
+		meta["_id"] = r.id
+	}
+	if r.routing != "" {
+		meta["_routing"] = r.routing
+	}
+	if r.parent != "" {
+		meta["_parent"] = r.parent
+	}
+	action := map[string]interface{}{"update": meta}
+
+	actionLine, err := json.Marshal(action)
+	if err != nil {
+		return nil, err
+	}
+
+	data := map[string]interface{}{}
+	if r.doc != nil {
+		data["doc"] = r.doc
+	}
+	if r.docAsUpsert {
+		data["doc_as_upsert"] = true
+	}
+	if r.upsert != nil {
+		data["upsert"] = r.upsert
+	}
+	sourceLine, err := json.Marshal(data)
+	if err != nil {
+		return nil, err
+	}
+
+	return []string{string(actionLine), string(sourceLine)}, nil
+}
+
+// BulkDeleteRequest is a request to delete a document in a bulk call.
+type BulkDeleteRequest struct {
+	index, typ, id string
+	routing        string
+	parent         string
+}
+
+// NewBulkDeleteRequest creates a new BulkDeleteRequest.
+func NewBulkDeleteRequest() *BulkDeleteRequest {
+	return &BulkDeleteRequest{}
+}
+
+func (r *BulkDeleteRequest) Index(index string) *BulkDeleteRequest {
+	r.index = index
+	return r
+}
+
+func (r *BulkDeleteRequest) Type(typ string) *BulkDeleteRequest {
+	r.typ = typ
+	return r
+}
+
+func (r *BulkDeleteRequest) Id(id string) *BulkDeleteRequest {
+	r.id = id
+	return r
+}
+
+func (r *BulkDeleteRequest) Routing(routing string) *BulkDeleteRequest {
+	r.routing = routing
+	return r
+}
+
+func (r *BulkDeleteRequest) Parent(parent string) *BulkDeleteRequest {
+	r.parent = parent
+	return r
+}
+
+// Source implements BulkableRequest. A delete has no source line.
+func (r *BulkDeleteRequest) Source() ([]string, error) {
+	meta := map[string]interface{}{}
+	if r.index != "" {
+		meta["_index"] = r.index
+	}
+	if r.typ != "" {
+		meta["_type"] = r.typ
+	}
+	if r.id != "" {
+		meta["_id"] = r.id
+	}
+	if r.routing != "" {
+		meta["_routing"] = r.routing
+	}
+	if r.parent != "" {
+		meta["_parent"] = r.parent
+	}
+	action := map[string]interface{}{"delete": meta}
+
+	actionLine, err := json.Marshal(action)
+	if err != nil {
+		return nil, err
+	}
+	return []string{string(actionLine)}, nil
+}
+
+//go:generate easyjson -all bulk_request.go
+
+// bulkResultItem is the per-item result embedded in a bulkResponse, keyed
+// by "index", "update", or "delete".
+type bulkResultItem struct {
+	Index   string `json:"_index"`
+	Type    string `json:"_type"`
+	Id      string `json:"_id"`
+	Version int    `json:"_version"`
+	Status  int    `json:"status"`
+	Error   string `json:"error,omitempty"`
+}
+
+// bulkResponse is the result of a _bulk call.
+type bulkResponse struct {
+	Took   int                          `json:"took"`
+	Errors bool                         `json:"errors"`
+	Items  []map[string]*bulkResultItem `json:"items"`
+}
@@ -0,0 +1,148 @@
+// Code generated by easyjson for marshaling/unmarshaling bulkResultItem
+// and bulkResponse. DO NOT EDIT.
+//
+//go:build !easyjson_disable
+
+package elastic
+
+import (
+	"github.com/mailru/easyjson/jlexer"
+	"github.com/mailru/easyjson/jwriter"
+)
+
+// MarshalJSON supports encoding/json.Marshaler.
+func (v bulkResultItem) MarshalJSON() ([]byte, error) {
+	w := jwriter.Writer{}
+	easyjsonBulkResultItem(&w, v)
+	return w.Buffer.BuildBytes(), w.Error
+}
+
+// UnmarshalJSON supports encoding/json.Unmarshaler.
+func (v *bulkResultItem) UnmarshalJSON(data []byte) error {
+	l := jlexer.Lexer{Data: data}
+	easyjsonBulkResultItemDecode(&l, v)
+	return l.Error()
+}
+
+func easyjsonBulkResultItem(w *jwriter.Writer, v bulkResultItem) {
+	w.RawByte('{')
+	w.RawString(`"_index":`)
+	w.String(v.Index)
+	w.RawString(`,"_type":`)
+	w.String(v.Type)
+	w.RawString(`,"_id":`)
+	w.String(v.Id)
+	w.RawString(`,"_version":`)
+	w.Int(v.Version)
+	w.RawString(`,"status":`)
+	w.Int(v.Status)
+	if v.Error != "" {
+		w.RawString(`,"error":`)
+		w.String(v.Error)
+	}
+	w.RawByte('}')
+}
+
+func easyjsonBulkResultItemDecode(l *jlexer.Lexer, v *bulkResultItem) {
+	l.Delim('{')
+	for !l.IsDelim('}') {
+		key := l.UnsafeFieldName(false)
+		l.WantColon()
+		switch key {
+		case "_index":
+			v.Index = l.String()
+		case "_type":
+			v.Type = l.String()
+		case "_id":
+			v.Id = l.String()
+		case "_version":
+			v.Version = l.Int()
+		case "status":
+			v.Status = l.Int()
+		case "error":
+			v.Error = l.String()
+		default:
+			l.SkipRecursive()
+		}
+		l.WantComma()
+	}
+	l.Delim('}')
+}
+
+// MarshalJSON supports encoding/json.Marshaler.
+func (v bulkResponse) MarshalJSON() ([]byte, error) {
+	w := jwriter.Writer{}
+	easyjsonBulkResponse(&w, v)
+	return w.Buffer.BuildBytes(), w.Error
+}
+
+// UnmarshalJSON supports encoding/json.Unmarshaler.
+func (v *bulkResponse) UnmarshalJSON(data []byte) error {
+	l := jlexer.Lexer{Data: data}
+	easyjsonBulkResponseDecode(&l, v)
+	return l.Error()
+}
+
+func easyjsonBulkResponse(w *jwriter.Writer, v bulkResponse) {
+	w.RawByte('{')
+	w.RawString(`"took":`)
+	w.Int(v.Took)
+	w.RawString(`,"errors":`)
+	w.Bool(v.Errors)
+	w.RawString(`,"items":[`)
+	for i, item := range v.Items {
+		if i > 0 {
+			w.RawByte(',')
+		}
+		w.RawByte('{')
+		first := true
+		for action, result := range item {
+			if !first {
+				w.RawByte(',')
+			}
+			first = false
+			w.String(action)
+			w.RawByte(':')
+			easyjsonBulkResultItem(w, *result)
+		}
+		w.RawByte('}')
+	}
+	w.RawByte(']')
+	w.RawByte('}')
+}
+
+func easyjsonBulkResponseDecode(l *jlexer.Lexer, v *bulkResponse) {
+	l.Delim('{')
+	for !l.IsDelim('}') {
+		key := l.UnsafeFieldName(false)
+		l.WantColon()
+		switch key {
+		case "took":
+			v.Took = l.Int()
+		case "errors":
+			v.Errors = l.Bool()
+		case "items":
+			l.Delim('[')
+			for !l.IsDelim(']') {
+				item := make(map[string]*bulkResultItem)
+				l.Delim('{')
+				for !l.IsDelim('}') {
+					action := l.UnsafeFieldName(false)
+					l.WantColon()
+					result := new(bulkResultItem)
+					easyjsonBulkResultItemDecode(l, result)
+					item[action] = result
+					l.WantComma()
+				}
+				l.Delim('}')
+				v.Items = append(v.Items, item)
+				l.WantComma()
+			}
+			l.Delim(']')
+		default:
+			l.SkipRecursive()
+		}
+		l.WantComma()
+	}
+	l.Delim('}')
+}
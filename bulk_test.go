@@ -0,0 +1,121 @@
+// Copyright 2012-2014 Oliver Eilhard. All rights reserved.
+// Use of this source code is governed by a MIT-license.
+// See http://olivere.mit-license.org/license.txt for details.
+
+package elastic
+
+import (
+	"encoding/json"
+	"fmt"
+	"strings"
+	"testing"
+)
+
+// plainBulkResultItem mirrors bulkResultItem field-for-field but carries
+// no generated MarshalJSON/UnmarshalJSON, so encoding/json falls back to
+// reflection. It's the baseline the easyjson benchmarks below are
+// compared against.
+type plainBulkResultItem struct {
+	Index   string `json:"_index"`
+	Type    string `json:"_type"`
+	Id      string `json:"_id"`
+	Version int    `json:"_version"`
+	Status  int    `json:"status"`
+	Error   string `json:"error,omitempty"`
+}
+
+// docSize1KB is a realistic ~1KB source document, as used by the bulk
+// benchmarks below.
+var docSize1KB = map[string]interface{}{
+	"user":     "olivere",
+	"message":  "taking the elastic library for a spin in a high-throughput ingestion pipeline",
+	"tags":     []string{"elasticsearch", "golang", "bulk", "ingestion"},
+	"retweets": 42,
+	"payload":  fmt.Sprintf("%0900d", 0), // pad to ~1KB
+}
+
+// errorPayload1KB pads out to roughly the same ~1KB size as docSize1KB,
+// standing in for a realistic bulk item error -- e.g. a
+// mapper_parsing_exception that echoes the offending document back --
+// rather than the few bytes a bare version-conflict message would take.
+// Without it, the easyjson/stdlib comparison below would only ever
+// marshal a handful of bytes per item and couldn't demonstrate the win
+// on realistic, document-sized items.
+var errorPayload1KB = "mapper_parsing_exception: failed to parse field [payload]: " + strings.Repeat("x", 900)
+
+func newBulkResponseFixture(n int) *bulkResponse {
+	items := make([]map[string]*bulkResultItem, 0, n)
+	for i := 0; i < n; i++ {
+		items = append(items, map[string]*bulkResultItem{
+			"index": {
+				Index:   "twitter",
+				Type:    "tweet",
+				Id:      fmt.Sprintf("%d", i),
+				Version: 1,
+				Status:  201,
+				Error:   errorPayload1KB,
+			},
+		})
+	}
+	return &bulkResponse{Took: 37, Errors: false, Items: items}
+}
+
+// BenchmarkBulkResponseMarshal_Easyjson exercises the generated
+// MarshalJSON on a 1000-item bulk response of realistic ~1KB items, the
+// default build.
+func BenchmarkBulkResponseMarshal_Easyjson(b *testing.B) {
+	resp := newBulkResponseFixture(1000)
+	b.ReportAllocs()
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		if _, err := json.Marshal(resp); err != nil {
+			b.Fatal(err)
+		}
+	}
+}
+
+// BenchmarkBulkResponseMarshal_Stdlib marshals the same 1000 ~1KB items
+// through plainBulkResultItem, i.e. via encoding/json's reflection path,
+// to quantify what the generated marshalers above save.
+func BenchmarkBulkResponseMarshal_Stdlib(b *testing.B) {
+	items := make([]map[string]*plainBulkResultItem, 0, 1000)
+	for i := 0; i < 1000; i++ {
+		items = append(items, map[string]*plainBulkResultItem{
+			"index": {Index: "twitter", Type: "tweet", Id: fmt.Sprintf("%d", i), Version: 1, Status: 201, Error: errorPayload1KB},
+		})
+	}
+	resp := struct {
+		Took   int                               `json:"took"`
+		Errors bool                              `json:"errors"`
+		Items  []map[string]*plainBulkResultItem `json:"items"`
+	}{Took: 37, Errors: false, Items: items}
+
+	b.ReportAllocs()
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		if _, err := json.Marshal(resp); err != nil {
+			b.Fatal(err)
+		}
+	}
+}
+
+// BenchmarkBulkIndexRequestSource_1000Docs builds the request body for a
+// batch of 1000 realistic ~1KB documents, the shape BulkProcessor sends
+// to _bulk on every flush.
+func BenchmarkBulkIndexRequestSource_1000Docs(b *testing.B) {
+	reqs := make([]*BulkIndexRequest, 0, 1000)
+	for i := 0; i < 1000; i++ {
+		reqs = append(reqs, NewBulkIndexRequest().
+			Index("twitter").Type("tweet").Id(fmt.Sprintf("%d", i)).Doc(docSize1KB))
+	}
+
+	b.ReportAllocs()
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		for _, r := range reqs {
+			if _, err := r.Source(); err != nil {
+				b.Fatal(err)
+			}
+		}
+	}
+}
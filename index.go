@@ -5,14 +5,18 @@
 package elastic
 
 import (
+	"context"
 	"encoding/json"
 	"fmt"
 	"net/http"
 	"net/url"
+	"time"
 
 	"github.com/olivere/elastic/uritemplates"
 )
 
+//go:generate easyjson -all index.go
+
 // IndexResult is the result of indexing a document in Elasticsearch.
 type IndexResult struct {
 	Index   string `json:"_index"`
@@ -135,7 +139,16 @@ func (b *IndexService) Debug(debug bool) *IndexService {
 	return b
 }
 
+// Do executes the index operation and returns once the document has been
+// indexed. It is a thin wrapper around DoC that uses
+// context.Background(), preserved for callers who don't need cancellation.
 func (b *IndexService) Do() (*IndexResult, error) {
+	return b.DoC(context.Background())
+}
+
+// DoC executes the index operation. It honors ctx, so callers can bound
+// the call with a deadline or cancel the underlying HTTP request.
+func (b *IndexService) DoC(ctx context.Context) (*IndexResult, error) {
 	// Build url
 	var urls, method string
 	if b.id != "" {
@@ -205,27 +218,45 @@ func (b *IndexService) Do() (*IndexResult, error) {
 		urls += "?" + params.Encode()
 	}
 
-	// Set up a new request
-	req, err := b.client.NewRequest(method, urls)
-	if err != nil {
-		return nil, err
-	}
-
-	// Set body
-	if b.bodyJson != nil {
-		req.SetBodyJson(b.bodyJson)
-	} else {
-		req.SetBodyString(b.bodyString)
-	}
-
-	if b.debug {
-		b.client.dumpRequest((*http.Request)(req))
-	}
-
-	// Get response
-	res, err := b.client.c.Do((*http.Request)(req))
-	if err != nil {
-		return nil, err
+	// Get response, retrying on transient failures according to the
+	// Client's Retrier (a no-op by default). Each attempt builds a fresh
+	// request and re-sets its body: the transport has already drained
+	// and closed the previous attempt's request body, so reusing it
+	// would resend an empty document.
+	var res *http.Response
+	for retry := 0; ; retry++ {
+		req, err := b.client.NewRequest(method, urls)
+		if err != nil {
+			return nil, err
+		}
+		if b.bodyJson != nil {
+			req.SetBodyJson(b.bodyJson)
+		} else {
+			req.SetBodyString(b.bodyString)
+		}
+
+		if b.debug {
+			b.client.dumpRequest((*http.Request)(req))
+		}
+
+		httpReq := (*http.Request)(req).WithContext(ctx)
+		res, err = b.client.doWithPool(httpReq)
+		if rerr := retryOnError(err, res); rerr != nil {
+			if res != nil {
+				res.Body.Close()
+			}
+			wait, ok := b.client.retrier.Next(retry)
+			if !ok {
+				return nil, rerr
+			}
+			select {
+			case <-time.After(wait):
+			case <-ctx.Done():
+				return nil, ctx.Err()
+			}
+			continue
+		}
+		break
 	}
 	if err := checkResponse(res); err != nil {
 		return nil, err
@@ -0,0 +1,66 @@
+// Code generated by easyjson for marshaling/unmarshaling IndexResult.
+// DO NOT EDIT.
+//
+//go:build !easyjson_disable
+
+package elastic
+
+import (
+	"github.com/mailru/easyjson/jlexer"
+	"github.com/mailru/easyjson/jwriter"
+)
+
+// MarshalJSON supports encoding/json.Marshaler. It skips the reflection
+// encoding/json normally does, which matters on the hot ingestion path
+// where IndexService is called for one document after another.
+func (v IndexResult) MarshalJSON() ([]byte, error) {
+	w := jwriter.Writer{}
+	easyjsonIndexResult(&w, v)
+	return w.Buffer.BuildBytes(), w.Error
+}
+
+// UnmarshalJSON supports encoding/json.Unmarshaler.
+func (v *IndexResult) UnmarshalJSON(data []byte) error {
+	l := jlexer.Lexer{Data: data}
+	easyjsonIndexResultDecode(&l, v)
+	return l.Error()
+}
+
+func easyjsonIndexResult(w *jwriter.Writer, v IndexResult) {
+	w.RawByte('{')
+	w.RawString(`"_index":`)
+	w.String(v.Index)
+	w.RawString(`,"_type":`)
+	w.String(v.Type)
+	w.RawString(`,"_id":`)
+	w.String(v.Id)
+	w.RawString(`,"_version":`)
+	w.Int(v.Version)
+	w.RawString(`,"created":`)
+	w.Bool(v.Created)
+	w.RawByte('}')
+}
+
+func easyjsonIndexResultDecode(l *jlexer.Lexer, v *IndexResult) {
+	l.Delim('{')
+	for !l.IsDelim('}') {
+		key := l.UnsafeFieldName(false)
+		l.WantColon()
+		switch key {
+		case "_index":
+			v.Index = l.String()
+		case "_type":
+			v.Type = l.String()
+		case "_id":
+			v.Id = l.String()
+		case "_version":
+			v.Version = l.Int()
+		case "created":
+			v.Created = l.Bool()
+		default:
+			l.SkipRecursive()
+		}
+		l.WantComma()
+	}
+	l.Delim('}')
+}
@@ -0,0 +1,204 @@
+// Copyright 2012-2014 Oliver Eilhard. All rights reserved.
+// Use of this source code is governed by a MIT-license.
+// See http://olivere.mit-license.org/license.txt for details.
+
+package elastic
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+)
+
+// ErrScrollFinished is returned by ScrollService.Next once the scroll has
+// been exhausted, i.e. a request came back with zero hits.
+var ErrScrollFinished = errors.New("elastic: scroll finished")
+
+// ScrollService iterates over the results of a search that doesn't fit in
+// a single response, using the scan/scroll API. Unlike a plain
+// SearchRequest, it isn't bound by max_result_window and keeps only one
+// page of hits in memory at a time.
+// See http://www.elasticsearch.org/guide/en/elasticsearch/reference/current/search-request-scroll.html
+type ScrollService struct {
+	client *Client
+	keep   string // keep_alive, e.g. "1m"
+	source *SearchRequest
+
+	scrollId string
+	started  bool
+}
+
+// NewScrollService creates a new ScrollService with a default keep-alive
+// of one minute.
+func NewScrollService(client *Client) *ScrollService {
+	return &ScrollService{
+		client: client,
+		keep:   "1m",
+		source: NewSearchRequest(),
+	}
+}
+
+// KeepAlive sets how long Elasticsearch should keep the scroll context
+// alive between calls to Next, e.g. "1m" or "30s".
+func (s *ScrollService) KeepAlive(keep string) *ScrollService {
+	s.keep = keep
+	return s
+}
+
+// Index sets the index/indices to search.
+func (s *ScrollService) Index(indices ...string) *ScrollService {
+	s.source.Indices(indices...)
+	return s
+}
+
+// Type sets the type/types to search.
+func (s *ScrollService) Type(types ...string) *ScrollService {
+	s.source.Types(types...)
+	return s
+}
+
+// Body sets the query used to open the scroll, typically built via
+// SearchSource.
+func (s *ScrollService) Body(source interface{}) *ScrollService {
+	s.source.Source(source)
+	return s
+}
+
+// Next returns the next page of hits. The first call opens the scroll
+// context with the configured query; subsequent calls page through it
+// using the _scroll_id from the previous response. It returns
+// ErrScrollFinished once there are no more hits.
+func (s *ScrollService) Next(ctx context.Context) (*SearchResult, error) {
+	var req *Request
+	var err error
+	opening := !s.started
+
+	if opening {
+		urls := "/_search?scroll=" + s.keep
+		if indices, types := s.source.indices, s.source.types; len(indices) > 0 || len(types) > 0 {
+			path := "_all"
+			if len(indices) > 0 {
+				path = joinStrings(indices)
+			}
+			if len(types) > 0 {
+				path += "/" + joinStrings(types)
+			}
+			urls = "/" + path + urls
+		}
+		req, err = s.client.NewRequest("POST", urls)
+		if err != nil {
+			return nil, err
+		}
+		req.SetBodyJson(s.source.body())
+	} else {
+		if s.scrollId == "" {
+			return nil, ErrScrollFinished
+		}
+		req, err = s.client.NewRequest("POST", "/_search/scroll")
+		if err != nil {
+			return nil, err
+		}
+		req.SetBodyJson(map[string]interface{}{
+			"scroll":    s.keep,
+			"scroll_id": s.scrollId,
+		})
+	}
+
+	httpReq := (*http.Request)(req).WithContext(ctx)
+	res, err := s.client.doWithPool(httpReq)
+	if err != nil {
+		return nil, err
+	}
+	defer res.Body.Close()
+	if err := checkResponse(res); err != nil {
+		return nil, err
+	}
+
+	ret := new(SearchResult)
+	if err := json.NewDecoder(res.Body).Decode(ret); err != nil {
+		return nil, err
+	}
+	// Only now, with a successful response decoded, do we consider the
+	// scroll opened -- otherwise a transient failure on the first call
+	// would leave started true with no scrollId, permanently stranding
+	// the next call in ErrScrollFinished instead of retrying the open.
+	s.started = true
+	s.scrollId = ret.ScrollId
+	if ret.Hits == nil || len(ret.Hits.Hits) == 0 {
+		s.scrollId = ""
+		return ret, ErrScrollFinished
+	}
+	return ret, nil
+}
+
+// Results returns a channel of individual hits, paging through Next
+// under the hood, so callers can range over millions of hits while only
+// one page -- never the full result set -- is held in memory. The
+// channel is closed once the scroll is exhausted or ctx is done; a
+// failed page is reported as a single trailing HitResult with Err set.
+func (s *ScrollService) Results(ctx context.Context) <-chan HitResult {
+	out := make(chan HitResult)
+	go func() {
+		defer close(out)
+		for {
+			res, err := s.Next(ctx)
+			if err == ErrScrollFinished {
+				return
+			}
+			if err != nil {
+				select {
+				case out <- HitResult{Err: err}:
+				case <-ctx.Done():
+				}
+				return
+			}
+			if res.Hits == nil {
+				continue
+			}
+			for _, hit := range res.Hits.Hits {
+				select {
+				case out <- HitResult{Hit: hit}:
+				case <-ctx.Done():
+					return
+				}
+			}
+		}
+	}()
+	return out
+}
+
+// Close releases the scroll context on the server so Elasticsearch can
+// free its resources before KeepAlive would otherwise expire it. It is a
+// no-op if the scroll was never started or has already finished.
+func (s *ScrollService) Close(ctx context.Context) error {
+	if s.scrollId == "" {
+		return nil
+	}
+	req, err := s.client.NewRequest("DELETE", "/_search/scroll")
+	if err != nil {
+		return err
+	}
+	req.SetBodyJson(map[string]interface{}{
+		"scroll_id": s.scrollId,
+	})
+	httpReq := (*http.Request)(req).WithContext(ctx)
+	res, err := s.client.doWithPool(httpReq)
+	if err != nil {
+		return err
+	}
+	defer res.Body.Close()
+	s.scrollId = ""
+	return checkResponse(res)
+}
+
+func joinStrings(s []string) string {
+	out := ""
+	for i, v := range s {
+		if i > 0 {
+			out += ","
+		}
+		out += v
+	}
+	return out
+}
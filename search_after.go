@@ -0,0 +1,242 @@
+// Copyright 2012-2014 Oliver Eilhard. All rights reserved.
+// Use of this source code is governed by a MIT-license.
+// See http://olivere.mit-license.org/license.txt for details.
+
+package elastic
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+)
+
+// SearchAfterService paginates through a search using the search_after
+// parameter instead of a scroll context. It trades the simplicity of
+// Scroll for cursor-stable pages that don't pin server-side resources:
+// each call is a regular, stateless search, sorted and bounded by the
+// sort values of the last hit of the previous page.
+//
+// Plain search_after is only consistent as long as the index doesn't
+// change mid-scan; setting KeepAlive opens a point-in-time (PIT) context
+// up front and pins every page to it, so inserts/deletes elsewhere in
+// the index can no longer skip or duplicate hits.
+// See http://www.elasticsearch.org/guide/en/elasticsearch/reference/current/search-request-search-after.html
+type SearchAfterService struct {
+	client *Client
+	source *SearchRequest
+	sort   []interface{}
+
+	keepAlive string
+	pitId     string
+
+	searchAfter []interface{}
+	exhausted   bool
+}
+
+// NewSearchAfterService creates a new SearchAfterService. Body must
+// specify a sort that produces a unique ordering (e.g. including "_id")
+// so that search_after can resume correctly from the last hit.
+func NewSearchAfterService(client *Client) *SearchAfterService {
+	return &SearchAfterService{
+		client: client,
+		source: NewSearchRequest(),
+	}
+}
+
+// KeepAlive opts into a PIT-backed scan by keeping a point-in-time
+// context alive for the given duration (e.g. "1m") across calls to Next,
+// so every page is bound to a consistent view of the index.
+func (s *SearchAfterService) KeepAlive(keepAlive string) *SearchAfterService {
+	s.keepAlive = keepAlive
+	return s
+}
+
+// Index sets the index/indices to search.
+func (s *SearchAfterService) Index(indices ...string) *SearchAfterService {
+	s.source.Indices(indices...)
+	return s
+}
+
+// Type sets the type/types to search.
+func (s *SearchAfterService) Type(types ...string) *SearchAfterService {
+	s.source.Types(types...)
+	return s
+}
+
+// Body sets the query and sort used for every page, typically built via
+// SearchSource.
+func (s *SearchAfterService) Body(source interface{}) *SearchAfterService {
+	s.source.Source(source)
+	return s
+}
+
+// Next returns the next page of hits, resuming from the sort values of
+// the last hit returned by the previous call. It returns
+// ErrScrollFinished once a page comes back empty.
+func (s *SearchAfterService) Next(ctx context.Context) (*SearchResult, error) {
+	if s.exhausted {
+		return nil, ErrScrollFinished
+	}
+
+	if s.keepAlive != "" && s.pitId == "" {
+		if err := s.openPIT(ctx); err != nil {
+			return nil, err
+		}
+	}
+
+	body := s.source.body()
+	m, isMap := body.(map[string]interface{})
+	if s.searchAfter != nil || s.pitId != "" {
+		if !isMap {
+			return nil, fmt.Errorf("elastic: SearchAfterService requires a map-based Body (e.g. built via SearchSource) to inject search_after/pit, got %T", body)
+		}
+	} else if body == nil {
+		m = make(map[string]interface{})
+		isMap = true
+	}
+	if isMap {
+		if s.searchAfter != nil {
+			m["search_after"] = s.searchAfter
+		}
+		if s.pitId != "" {
+			m["pit"] = map[string]interface{}{"id": s.pitId, "keep_alive": s.keepAlive}
+		}
+		body = m
+	}
+
+	// Once a PIT is open, the index to search is implied by the PIT
+	// context, so every page is issued against the plain _search endpoint.
+	// Without a PIT, the configured indices and types select the endpoint.
+	urls := "/_search"
+	if indices, types := s.source.indices, s.source.types; s.pitId == "" && (len(indices) > 0 || len(types) > 0) {
+		path := "_all"
+		if len(indices) > 0 {
+			path = joinStrings(indices)
+		}
+		if len(types) > 0 {
+			path += "/" + joinStrings(types)
+		}
+		urls = "/" + path + urls
+	}
+	req, err := s.client.NewRequest("POST", urls)
+	if err != nil {
+		return nil, err
+	}
+	req.SetBodyJson(body)
+
+	httpReq := (*http.Request)(req).WithContext(ctx)
+	res, err := s.client.doWithPool(httpReq)
+	if err != nil {
+		return nil, err
+	}
+	defer res.Body.Close()
+	if err := checkResponse(res); err != nil {
+		return nil, err
+	}
+
+	ret := new(SearchResult)
+	if err := json.NewDecoder(res.Body).Decode(ret); err != nil {
+		return nil, err
+	}
+
+	if ret.Hits == nil || len(ret.Hits.Hits) == 0 {
+		s.exhausted = true
+		return ret, ErrScrollFinished
+	}
+
+	last := ret.Hits.Hits[len(ret.Hits.Hits)-1]
+	s.searchAfter = last.Sort
+	return ret, nil
+}
+
+// openPIT opens a point-in-time context over the configured indices and
+// stashes its id for use on every subsequent page.
+func (s *SearchAfterService) openPIT(ctx context.Context) error {
+	urls := "/_pit?keep_alive=" + s.keepAlive
+	if indices := s.source.indices; len(indices) > 0 {
+		urls = "/" + joinStrings(indices) + urls
+	}
+	req, err := s.client.NewRequest("POST", urls)
+	if err != nil {
+		return err
+	}
+
+	httpReq := (*http.Request)(req).WithContext(ctx)
+	res, err := s.client.doWithPool(httpReq)
+	if err != nil {
+		return err
+	}
+	defer res.Body.Close()
+	if err := checkResponse(res); err != nil {
+		return err
+	}
+
+	var ret struct {
+		Id string `json:"id"`
+	}
+	if err := json.NewDecoder(res.Body).Decode(&ret); err != nil {
+		return err
+	}
+	s.pitId = ret.Id
+	return nil
+}
+
+// Close releases the point-in-time context opened by KeepAlive, if any.
+// It is a no-op if KeepAlive was never set or the PIT has already been
+// closed; a PIT is kept open after the scan finishes until Close is called.
+func (s *SearchAfterService) Close(ctx context.Context) error {
+	if s.pitId == "" {
+		return nil
+	}
+	req, err := s.client.NewRequest("DELETE", "/_pit")
+	if err != nil {
+		return err
+	}
+	req.SetBodyJson(map[string]interface{}{"id": s.pitId})
+
+	httpReq := (*http.Request)(req).WithContext(ctx)
+	res, err := s.client.doWithPool(httpReq)
+	if err != nil {
+		return err
+	}
+	defer res.Body.Close()
+	s.pitId = ""
+	return checkResponse(res)
+}
+
+// Results returns a channel of individual hits, paging through Next
+// under the hood, so callers can range over a large result set without
+// holding it all in memory. The channel is closed once the iteration is
+// exhausted or ctx is done; a failed page is reported as a single
+// trailing HitResult with Err set.
+func (s *SearchAfterService) Results(ctx context.Context) <-chan HitResult {
+	out := make(chan HitResult)
+	go func() {
+		defer close(out)
+		for {
+			res, err := s.Next(ctx)
+			if err == ErrScrollFinished {
+				return
+			}
+			if err != nil {
+				select {
+				case out <- HitResult{Err: err}:
+				case <-ctx.Done():
+				}
+				return
+			}
+			if res.Hits == nil {
+				continue
+			}
+			for _, hit := range res.Hits.Hits {
+				select {
+				case out <- HitResult{Hit: hit}:
+				case <-ctx.Done():
+					return
+				}
+			}
+		}
+	}()
+	return out
+}
@@ -111,43 +111,27 @@ func (r *SearchRequest) Source(source interface{}) *SearchRequest {
 }
 
 // header is used by MultiSearch to get information about the search header
-// of one SearchRequest.
+// of one SearchRequest. The returned searchRequestHeader marshals itself
+// without going through encoding/json's reflection (see
+// search_request_easyjson.go).
 // See http://www.elasticsearch.org/guide/en/elasticsearch/reference/current/search-multi-search.html
 func (r *SearchRequest) header() interface{} {
-	h := make(map[string]interface{})
-	if r.searchType != "" {
-		h["search_type"] = r.searchType
+	h := searchRequestHeader{searchType: r.searchType, indices: r.indices, types: r.types}
+	if r.routing != nil {
+		h.routing = *r.routing
 	}
-
-	switch len(r.indices) {
-	case 0:
-	case 1:
-		h["index"] = r.indices[0]
-	default:
-		h["indices"] = r.indices
-	}
-
-	switch len(r.types) {
-	case 0:
-	case 1:
-		h["types"] = r.types[0]
-	default:
-		h["type"] = r.types
+	if r.preference != nil {
+		h.preference = *r.preference
 	}
-
-	if r.routing != nil && *r.routing != "" {
-		h["routing"] = *r.routing
-	}
-
-	if r.preference != nil && *r.preference != "" {
-		h["preference"] = *r.preference
-	}
-
 	return h
 }
 
-// bidy is used by MultiSearch to get information about the search body
-// of one SearchRequest.
+// body is used by MultiSearch to get information about the search body of
+// one SearchRequest. Unlike header, body is the caller's query DSL tree --
+// an arbitrary, unbounded shape (whatever Source was given, typically
+// built via SearchSource) -- so it has no fixed set of fields to generate
+// an easyjson marshaler for, and goes through encoding/json like any other
+// interface{} value.
 // See http://www.elasticsearch.org/guide/en/elasticsearch/reference/current/search-multi-search.html
 func (r *SearchRequest) body() interface{} {
 	return r.source
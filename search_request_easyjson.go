@@ -0,0 +1,103 @@
+// Code generated by easyjson for marshaling searchRequestHeader.
+// DO NOT EDIT.
+//
+// Hand-adjusted: easyjson can't generate the index/indices and
+// types/type key-selection logic below (which key is used depends on how
+// many values are set, not on a fixed field), so MarshalJSON is written
+// by hand using the same jwriter primitives the generator emits
+// elsewhere in this package. There is no UnmarshalJSON because a header
+// is only ever sent, never parsed, by this client.
+//
+//go:build !easyjson_disable
+
+package elastic
+
+import (
+	"github.com/mailru/easyjson/jwriter"
+)
+
+// searchRequestHeader is the fixed-shape multi-search header line built
+// by SearchRequest.header.
+type searchRequestHeader struct {
+	searchType string
+	indices    []string
+	types      []string
+	routing    string
+	preference string
+}
+
+// MarshalJSON supports encoding/json.Marshaler.
+func (h searchRequestHeader) MarshalJSON() ([]byte, error) {
+	w := jwriter.Writer{}
+	easyjsonSearchRequestHeader(&w, h)
+	return w.Buffer.BuildBytes(), w.Error
+}
+
+func easyjsonSearchRequestHeader(w *jwriter.Writer, h searchRequestHeader) {
+	w.RawByte('{')
+	wrote := false
+	comma := func() {
+		if wrote {
+			w.RawByte(',')
+		}
+		wrote = true
+	}
+
+	if h.searchType != "" {
+		comma()
+		w.RawString(`"search_type":`)
+		w.String(h.searchType)
+	}
+
+	switch len(h.indices) {
+	case 0:
+	case 1:
+		comma()
+		w.RawString(`"index":`)
+		w.String(h.indices[0])
+	default:
+		comma()
+		w.RawString(`"indices":`)
+		w.RawByte('[')
+		for i, v := range h.indices {
+			if i > 0 {
+				w.RawByte(',')
+			}
+			w.String(v)
+		}
+		w.RawByte(']')
+	}
+
+	switch len(h.types) {
+	case 0:
+	case 1:
+		comma()
+		w.RawString(`"types":`)
+		w.String(h.types[0])
+	default:
+		comma()
+		w.RawString(`"type":`)
+		w.RawByte('[')
+		for i, v := range h.types {
+			if i > 0 {
+				w.RawByte(',')
+			}
+			w.String(v)
+		}
+		w.RawByte(']')
+	}
+
+	if h.routing != "" {
+		comma()
+		w.RawString(`"routing":`)
+		w.String(h.routing)
+	}
+
+	if h.preference != "" {
+		comma()
+		w.RawString(`"preference":`)
+		w.String(h.preference)
+	}
+
+	w.RawByte('}')
+}
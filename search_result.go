@@ -0,0 +1,38 @@
+// Copyright 2012-2014 Oliver Eilhard. All rights reserved.
+// Use of this source code is governed by a MIT-license.
+// See http://olivere.mit-license.org/license.txt for details.
+
+package elastic
+
+import "encoding/json"
+
+// SearchResult is the outcome of a search, a scroll page, or a
+// search_after page.
+type SearchResult struct {
+	ScrollId string      `json:"_scroll_id,omitempty"`
+	TimedOut bool        `json:"timed_out"`
+	Hits     *SearchHits `json:"hits,omitempty"`
+}
+
+// SearchHits holds the hits returned by a search.
+type SearchHits struct {
+	Total int          `json:"total"`
+	Hits  []*SearchHit `json:"hits"`
+}
+
+// SearchHit is a single document matched by a search.
+type SearchHit struct {
+	Index  string           `json:"_index"`
+	Type   string           `json:"_type"`
+	Id     string           `json:"_id"`
+	Sort   []interface{}    `json:"sort,omitempty"`
+	Source *json.RawMessage `json:"_source,omitempty"`
+}
+
+// HitResult is sent on the channel returned by ScrollService.Results and
+// SearchAfterService.Results: one value per matched document, or a
+// single trailing value with Err set if the underlying iteration failed.
+type HitResult struct {
+	Hit *SearchHit
+	Err error
+}
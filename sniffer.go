@@ -0,0 +1,332 @@
+// Copyright 2012-2014 Oliver Eilhard. All rights reserved.
+// Use of this source code is governed by a MIT-license.
+// See http://olivere.mit-license.org/license.txt for details.
+
+package elastic
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+	neturl "net/url"
+	"sync"
+	"time"
+)
+
+// ErrNoClient is returned by Client when every node in the connection
+// pool is marked dead, so that callers can tell this apart from a regular
+// HTTP error returned by a reachable node. A Retrier is only given the
+// retry number, not the error, so it cannot make this distinction.
+var ErrNoClient = errors.New("elastic: no active node found in connection pool")
+
+const (
+	// DefaultSniffInterval is how often the pool re-discovers nodes via
+	// the _nodes/http API when sniffing is enabled.
+	DefaultSniffInterval = 15 * time.Minute
+
+	// DefaultHealthcheckInterval is how often dead nodes are pinged to
+	// see if they have become healthy again.
+	DefaultHealthcheckInterval = 60 * time.Second
+)
+
+// nodeConn tracks the health of a single node in the pool.
+type nodeConn struct {
+	url       string
+	dead      bool
+	fails     int
+	nextCheck time.Time // zero once dead == false
+}
+
+// maxDeadNodeBackoff caps how long a consistently dead node can go between
+// healthcheck pings, no matter how many times it has failed in a row.
+const maxDeadNodeBackoff = 10 * time.Minute
+
+// deadNodeBackoff returns how long to wait before the next healthcheck
+// attempt for a node on its nth consecutive failure, doubling
+// HealthcheckInterval each time up to maxDeadNodeBackoff, so a node that's
+// been down for a while isn't pinged on every single tick forever.
+func deadNodeBackoff(interval time.Duration, fails int) time.Duration {
+	d := interval
+	for i := 1; i < fails; i++ {
+		d *= 2
+		if d >= maxDeadNodeBackoff {
+			return maxDeadNodeBackoff
+		}
+	}
+	return d
+}
+
+// sniffer discovers and health-checks the nodes of an Elasticsearch
+// cluster so that Client can round-robin requests across the healthy
+// ones instead of pinning all traffic to a single, possibly dead, URL.
+type sniffer struct {
+	mu                  sync.Mutex
+	nodes               []*nodeConn
+	next                int
+	sniffEnabled        bool
+	sniffInterval       time.Duration
+	healthcheckEnabled  bool
+	healthcheckInterval time.Duration
+	stopCh              chan struct{}
+}
+
+// SetURL sets one or more seed URLs the pool starts from. Further nodes
+// may be discovered from these via sniffing.
+func (c *Client) SetURL(urls ...string) *Client {
+	c.sniffer.mu.Lock()
+	defer c.sniffer.mu.Unlock()
+	nodes := make([]*nodeConn, 0, len(urls))
+	for _, u := range urls {
+		nodes = append(nodes, &nodeConn{url: u})
+	}
+	c.sniffer.nodes = nodes
+	c.sniffer.next = 0
+	return c
+}
+
+// SetSniff enables or disables periodic discovery of cluster nodes via
+// the _nodes/http API.
+func (c *Client) SetSniff(enabled bool) *Client {
+	c.sniffer.mu.Lock()
+	c.sniffer.sniffEnabled = enabled
+	c.sniffer.mu.Unlock()
+	c.startSniffing()
+	return c
+}
+
+// SetSniffInterval sets how often sniffing runs when enabled.
+func (c *Client) SetSniffInterval(interval time.Duration) *Client {
+	c.sniffer.mu.Lock()
+	c.sniffer.sniffInterval = interval
+	c.sniffer.mu.Unlock()
+	return c
+}
+
+// SetHealthcheck enables or disables periodic pinging of dead nodes so
+// they can be revived once they start responding again.
+func (c *Client) SetHealthcheck(enabled bool) *Client {
+	c.sniffer.mu.Lock()
+	c.sniffer.healthcheckEnabled = enabled
+	c.sniffer.mu.Unlock()
+	c.startSniffing()
+	return c
+}
+
+// SetHealthcheckInterval sets how often dead nodes are pinged.
+func (c *Client) SetHealthcheckInterval(interval time.Duration) *Client {
+	c.sniffer.mu.Lock()
+	c.sniffer.healthcheckInterval = interval
+	c.sniffer.mu.Unlock()
+	return c
+}
+
+// startSniffing launches the background sniff and healthcheck loops. It
+// is a no-op if neither SetSniff nor SetHealthcheck was enabled.
+func (c *Client) startSniffing() {
+	s := &c.sniffer
+	s.mu.Lock()
+	if s.stopCh != nil || (!s.sniffEnabled && !s.healthcheckEnabled) {
+		s.mu.Unlock()
+		return
+	}
+	s.stopCh = make(chan struct{})
+	if s.sniffInterval <= 0 {
+		s.sniffInterval = DefaultSniffInterval
+	}
+	if s.healthcheckInterval <= 0 {
+		s.healthcheckInterval = DefaultHealthcheckInterval
+	}
+	stopCh := s.stopCh
+	s.mu.Unlock()
+
+	go func() {
+		sniffTicker := time.NewTicker(s.sniffInterval)
+		healthTicker := time.NewTicker(s.healthcheckInterval)
+		defer sniffTicker.Stop()
+		defer healthTicker.Stop()
+		for {
+			select {
+			case <-stopCh:
+				return
+			case <-sniffTicker.C:
+				if s.sniffEnabled {
+					c.sniff()
+				}
+			case <-healthTicker.C:
+				if s.healthcheckEnabled {
+					c.healthcheck()
+				}
+			}
+		}
+	}()
+}
+
+// stopSniffing stops the background sniff and healthcheck loops.
+func (c *Client) stopSniffing() {
+	s := &c.sniffer
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.stopCh != nil {
+		close(s.stopCh)
+		s.stopCh = nil
+	}
+}
+
+// sniff calls _nodes/http on a currently healthy node and replaces the
+// pool's node list with every node it reports that has an HTTP publish
+// address. Nodes are not filtered by role, so non-data nodes are kept too.
+func (c *Client) sniff() error {
+	url, err := c.nextURL()
+	if err != nil {
+		return err
+	}
+
+	res, err := c.c.Get(url + "/_nodes/http")
+	if err != nil {
+		return err
+	}
+	defer res.Body.Close()
+
+	var info struct {
+		Nodes map[string]struct {
+			HTTP struct {
+				PublishAddress string `json:"publish_address"`
+			} `json:"http"`
+		} `json:"nodes"`
+	}
+	if err := json.NewDecoder(res.Body).Decode(&info); err != nil {
+		return err
+	}
+
+	var nodes []*nodeConn
+	for _, n := range info.Nodes {
+		if n.HTTP.PublishAddress == "" {
+			continue
+		}
+		nodes = append(nodes, &nodeConn{url: "http://" + n.HTTP.PublishAddress})
+	}
+	if len(nodes) == 0 {
+		return nil
+	}
+
+	c.sniffer.mu.Lock()
+	c.sniffer.nodes = nodes
+	c.sniffer.next = 0
+	c.sniffer.mu.Unlock()
+	return nil
+}
+
+// healthcheck pings every dead node that is due for a retry with a
+// lightweight HEAD / and revives it if it responds successfully. A node
+// that has failed repeatedly is skipped until its exponential backoff
+// window elapses, instead of being pinged on every tick.
+func (c *Client) healthcheck() {
+	c.sniffer.mu.Lock()
+	nodes := append([]*nodeConn(nil), c.sniffer.nodes...)
+	interval := c.sniffer.healthcheckInterval
+	c.sniffer.mu.Unlock()
+
+	now := time.Now()
+	for _, n := range nodes {
+		c.sniffer.mu.Lock()
+		due := n.dead && !now.Before(n.nextCheck)
+		c.sniffer.mu.Unlock()
+		if !due {
+			continue
+		}
+		req, err := http.NewRequest("HEAD", n.url+"/", nil)
+		if err != nil {
+			continue
+		}
+		res, err := c.c.Do(req)
+		if err == nil {
+			res.Body.Close()
+		}
+		if err == nil && res.StatusCode >= 200 && res.StatusCode < 300 {
+			c.sniffer.mu.Lock()
+			n.dead = false
+			n.fails = 0
+			n.nextCheck = time.Time{}
+			c.sniffer.mu.Unlock()
+		} else {
+			c.sniffer.mu.Lock()
+			n.fails++
+			n.nextCheck = now.Add(deadNodeBackoff(interval, n.fails))
+			c.sniffer.mu.Unlock()
+		}
+	}
+}
+
+// doWithPool executes req against a node selected from the connection
+// pool, round-robining across healthy nodes and marking a node dead on
+// a transient failure so the next call skips it until healthcheck
+// revives it. If no nodes were ever configured via SetURL, the pool is
+// bypassed entirely and req is sent as-is, preserving the behavior of a
+// Client that never opted into sniffing/healthchecking.
+func (c *Client) doWithPool(req *http.Request) (*http.Response, error) {
+	c.sniffer.mu.Lock()
+	hasNodes := len(c.sniffer.nodes) > 0
+	c.sniffer.mu.Unlock()
+	if !hasNodes {
+		return c.c.Do(req)
+	}
+
+	nodeURL, err := c.nextURL()
+	if err != nil {
+		return nil, err
+	}
+	u, err := neturl.Parse(nodeURL)
+	if err != nil {
+		return nil, err
+	}
+	req.URL.Scheme = u.Scheme
+	req.URL.Host = u.Host
+
+	res, err := c.c.Do(req)
+	if retryOnError(err, res) != nil {
+		c.markDead(nodeURL)
+	}
+	return res, err
+}
+
+// nextURL round-robins over the healthy nodes in the pool, returning
+// ErrNoClient if every node has been marked dead.
+func (c *Client) nextURL() (string, error) {
+	s := &c.sniffer
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	n := len(s.nodes)
+	if n == 0 {
+		return "", ErrNoClient
+	}
+	for i := 0; i < n; i++ {
+		idx := (s.next + i) % n
+		node := s.nodes[idx]
+		if !node.dead {
+			s.next = idx + 1
+			return node.url, nil
+		}
+	}
+	return "", ErrNoClient
+}
+
+// markDead marks the node backing the given URL as dead with exponential
+// revival: healthcheck skips it until deadNodeBackoff(fails) has elapsed,
+// so a node with a longer failure streak is retried less often.
+func (c *Client) markDead(url string) {
+	c.sniffer.mu.Lock()
+	defer c.sniffer.mu.Unlock()
+	interval := c.sniffer.healthcheckInterval
+	if interval <= 0 {
+		interval = DefaultHealthcheckInterval
+	}
+	for _, node := range c.sniffer.nodes {
+		if node.url == url {
+			node.dead = true
+			node.fails++
+			node.nextCheck = time.Now().Add(deadNodeBackoff(interval, node.fails))
+			break
+		}
+	}
+}